@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// defaultIdempotencyTTL is how long a processed notification's idempotency
+// key is remembered when a Handler is configured with an IdempotencyStore
+// but no explicit TTL.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// Handler is an http.Handler that authenticates, deduplicates, and parses
+// Recurly webhook deliveries before publishing them to a Registry. Build one
+// with NewHandler.
+type Handler struct {
+	registry *Registry
+
+	username, password string
+	allowedNetworks    []*net.IPNet
+
+	idempotency    IdempotencyStore
+	idempotencyTTL time.Duration
+
+	journal Journal
+}
+
+// HandlerOption configures a Handler constructed with NewHandler.
+type HandlerOption func(*Handler) error
+
+// WithBasicAuth requires every request to present the given HTTP Basic
+// credentials, as configured on the Recurly webhook endpoint. Credentials
+// are compared in constant time.
+func WithBasicAuth(username, password string) HandlerOption {
+	return func(h *Handler) error {
+		h.username, h.password = username, password
+		return nil
+	}
+}
+
+// WithAllowedIPs restricts requests to the given IPs or CIDR ranges. A bare
+// IP (e.g. "52.40.75.10") is treated as a /32 or /128.
+func WithAllowedIPs(ips ...string) HandlerOption {
+	return func(h *Handler) error {
+		for _, raw := range ips {
+			network, err := parseIPOrCIDR(raw)
+			if err != nil {
+				return fmt.Errorf("webhooks: %v", err)
+			}
+			h.allowedNetworks = append(h.allowedNetworks, network)
+		}
+		return nil
+	}
+}
+
+// WithIdempotencyStore deduplicates deliveries against store, remembering
+// each processed notification for ttl. A zero ttl uses
+// defaultIdempotencyTTL.
+func WithIdempotencyStore(store IdempotencyStore, ttl time.Duration) HandlerOption {
+	return func(h *Handler) error {
+		h.idempotency = store
+		if ttl <= 0 {
+			ttl = defaultIdempotencyTTL
+		}
+		h.idempotencyTTL = ttl
+		return nil
+	}
+}
+
+// WithJournal appends every verified notification to journal before it is
+// published to the registry, so subscribers that register later with
+// Registry.SubscribeFrom can catch up on history. Pass the same Journal to
+// WithReplayJournal on the registry handling fan-out.
+func WithJournal(journal Journal) HandlerOption {
+	return func(h *Handler) error {
+		h.journal = journal
+		return nil
+	}
+}
+
+// NewHandler returns an http.Handler that verifies, deduplicates, and parses
+// incoming webhook requests, publishing the result to registry for delivery
+// to any subscribers. registry may be nil if the caller only cares about the
+// verification and idempotency behavior and will otherwise ignore fan-out.
+func NewHandler(registry *Registry, opts ...HandlerOption) (*Handler, error) {
+	h := &Handler{
+		registry:       registry,
+		idempotencyTTL: defaultIdempotencyTTL,
+	}
+
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.username != "" || h.password != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !constantTimeEqual(user, h.username) || !constantTimeEqual(pass, h.password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="recurly-webhooks"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if len(h.allowedNetworks) > 0 && !h.remoteAllowed(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	n, eventType, err := parse(body)
+	if err != nil {
+		if _, ok := err.(ErrUnknownNotification); ok {
+			// Unrecognized event types aren't an error the sender
+			// should retry over; acknowledge and move on.
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// CheckAndRecord happens before the journal write, so two concurrent
+	// deliveries of the same notification can't both observe "not seen"
+	// and both go on to journal and publish; whichever loses the race
+	// acknowledges without doing anything further. That does mean a
+	// delivery that fails to journal or publish after being recorded
+	// here won't be retried from a replayed webhook, only via
+	// reconciliation.
+	if h.idempotency != nil {
+		seen, err := h.idempotency.CheckAndRecord(idempotencyKey(eventType, n), h.idempotencyTTL)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if seen {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	if h.journal != nil {
+		if _, err := h.journal.Append(body, n, time.Now()); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if h.registry != nil {
+		h.registry.publish(eventType, n)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) remoteAllowed(r *http.Request) bool {
+	host := r.RemoteAddr
+	if hostOnly, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = hostOnly
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range h.allowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func parseIPOrCIDR(raw string) (*net.IPNet, error) {
+	if strings.Contains(raw, "/") {
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %v", raw, err)
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP %q", raw)
+	}
+
+	bits := 32
+	if ip.To4() == nil {
+		bits = 128
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// idempotencyKey derives a stable hash for a parsed notification from its
+// account code, transaction UUID, invoice number, and gift card redemption
+// code (whichever are present), plus its event type. Notification structs
+// aren't required to implement a dedicated interface for this - the fields
+// are read via reflection off whichever of the Account, Transaction,
+// Invoice, and GiftCard fields the struct has, so new notification types
+// added to the switch in parse that use those field names need no changes
+// here. No other field is part of the key: two notifications of the same
+// type that differ only elsewhere (e.g. in Subscription) share a key.
+func idempotencyKey(eventType string, n interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(n))
+
+	var accountCode, transactionUUID, redemptionCode string
+	var invoiceNumber int
+
+	if v.Kind() == reflect.Struct {
+		if account := v.FieldByName("Account"); account.IsValid() {
+			if code := account.FieldByName("Code"); code.IsValid() && code.Kind() == reflect.String {
+				accountCode = code.String()
+			}
+		}
+
+		if txn := v.FieldByName("Transaction"); txn.IsValid() {
+			if uuid := txn.FieldByName("UUID"); uuid.IsValid() && uuid.Kind() == reflect.String {
+				transactionUUID = uuid.String()
+			}
+			if num := txn.FieldByName("InvoiceNumber"); num.IsValid() && num.Kind() == reflect.Int {
+				invoiceNumber = int(num.Int())
+			}
+		}
+
+		if inv := v.FieldByName("Invoice"); inv.IsValid() {
+			if num := inv.FieldByName("InvoiceNumber"); num.IsValid() && num.Kind() == reflect.Int {
+				invoiceNumber = int(num.Int())
+			}
+		}
+
+		if gc := v.FieldByName("GiftCard"); gc.IsValid() {
+			if code := gc.FieldByName("RedemptionCode"); code.IsValid() && code.Kind() == reflect.String {
+				redemptionCode = code.String()
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d|%s", eventType, accountCode, transactionUUID, invoiceNumber, redemptionCode)))
+	return hex.EncodeToString(sum[:])
+}
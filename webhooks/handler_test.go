@@ -0,0 +1,62 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/blacklightcms/recurly"
+)
+
+func TestIdempotencyKeyDistinguishesInvoices(t *testing.T) {
+	account := recurly.Account{Code: "acct-1"}
+
+	first := &NewInvoiceNotification{
+		Account: account,
+		Invoice: recurly.Invoice{InvoiceNumber: 1001},
+	}
+	second := &NewInvoiceNotification{
+		Account: account,
+		Invoice: recurly.Invoice{InvoiceNumber: 1002},
+	}
+
+	firstKey := idempotencyKey(NewInvoice, first)
+	secondKey := idempotencyKey(NewInvoice, second)
+
+	if firstKey == secondKey {
+		t.Fatalf("idempotencyKey returned the same key %q for two distinct invoices", firstKey)
+	}
+
+	if got := idempotencyKey(NewInvoice, first); got != firstKey {
+		t.Fatalf("idempotencyKey(%v) = %q, want %q (same invoice should always produce the same key)", first, got, firstKey)
+	}
+}
+
+func TestIdempotencyKeyDistinguishesGiftCards(t *testing.T) {
+	first := &PurchasedGiftCardNotification{
+		GiftCard: GiftCard{RedemptionCode: "ABC123"},
+	}
+	second := &PurchasedGiftCardNotification{
+		GiftCard: GiftCard{RedemptionCode: "XYZ789"},
+	}
+
+	firstKey := idempotencyKey(PurchasedGiftCard, first)
+	secondKey := idempotencyKey(PurchasedGiftCard, second)
+
+	if firstKey == secondKey {
+		t.Fatalf("idempotencyKey returned the same key %q for two distinct gift cards", firstKey)
+	}
+
+	if got := idempotencyKey(PurchasedGiftCard, first); got != firstKey {
+		t.Fatalf("idempotencyKey(%v) = %q, want %q (same gift card should always produce the same key)", first, got, firstKey)
+	}
+}
+
+func TestIdempotencyKeyDistinguishesEventTypes(t *testing.T) {
+	n := &NewInvoiceNotification{
+		Account: recurly.Account{Code: "acct-1"},
+		Invoice: recurly.Invoice{InvoiceNumber: 1001},
+	}
+
+	if got, want := idempotencyKey(NewInvoice, n), idempotencyKey(ProcessingInvoice, n); got == want {
+		t.Fatalf("idempotencyKey returned the same key %q for different event types", got)
+	}
+}
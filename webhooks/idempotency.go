@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// IdempotencyStore lets a Handler recognize webhook deliveries it has
+// already processed, so Recurly's at-least-once retry behavior doesn't
+// cause downstream subscribers to fire twice for the same event.
+//
+// CheckAndRecord atomically checks whether key has already been recorded
+// and, if not, records it as processed for ttl, returning whether it was
+// already seen. The check and the record must happen as a single atomic
+// operation so that two concurrent deliveries of the same key can't both
+// observe "not seen" and both go on to publish.
+//
+// NewLRUIdempotencyStore provides an in-memory implementation suitable for
+// a single process. For multi-instance deployments, back this interface
+// with Redis (SET key NX PX ttl, where the NX flag makes the check and the
+// record atomic) or a SQL table with a unique key column, using the
+// uniqueness constraint violation to detect a duplicate, and an
+// expires_at index swept periodically.
+type IdempotencyStore interface {
+	CheckAndRecord(key string, ttl time.Duration) (alreadySeen bool, err error)
+}
+
+// LRUIdempotencyStore is an in-memory IdempotencyStore bounded by entry
+// count. Once full, the least recently touched key is evicted to make room
+// for new ones.
+type LRUIdempotencyStore struct {
+	mu       sync.Mutex
+	size     int
+	ll       *list.List
+	elements map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// NewLRUIdempotencyStore creates an LRUIdempotencyStore holding at most size
+// entries.
+func NewLRUIdempotencyStore(size int) *LRUIdempotencyStore {
+	return &LRUIdempotencyStore{
+		size:     size,
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// CheckAndRecord implements IdempotencyStore. A zero ttl means the key
+// never expires on its own, though it may still be evicted once size is
+// exceeded.
+func (s *LRUIdempotencyStore) CheckAndRecord(key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.elements[key]; ok {
+		entry := el.Value.(*lruEntry)
+		if entry.expiresAt.IsZero() || !time.Now().After(entry.expiresAt) {
+			s.ll.MoveToFront(el)
+			return true, nil
+		}
+		// Expired; fall through and treat this key as unseen.
+		s.ll.Remove(el)
+		delete(s.elements, key)
+	}
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	el := s.ll.PushFront(&lruEntry{key: key, expiresAt: expiresAt})
+	s.elements[key] = el
+
+	if s.size > 0 && s.ll.Len() > s.size {
+		oldest := s.ll.Back()
+		if oldest != nil {
+			s.ll.Remove(oldest)
+			delete(s.elements, oldest.Value.(*lruEntry).key)
+		}
+	}
+
+	return false, nil
+}
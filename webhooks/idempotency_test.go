@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUIdempotencyStoreCheckAndRecord(t *testing.T) {
+	store := NewLRUIdempotencyStore(8)
+
+	seen, err := store.CheckAndRecord("key-1", time.Hour)
+	if err != nil {
+		t.Fatalf("CheckAndRecord: %v", err)
+	}
+	if seen {
+		t.Fatalf("CheckAndRecord reported key-1 already seen on first call")
+	}
+
+	seen, err = store.CheckAndRecord("key-1", time.Hour)
+	if err != nil {
+		t.Fatalf("CheckAndRecord: %v", err)
+	}
+	if !seen {
+		t.Fatalf("CheckAndRecord did not report key-1 as already seen on second call")
+	}
+}
+
+func TestLRUIdempotencyStoreCheckAndRecordExpires(t *testing.T) {
+	store := NewLRUIdempotencyStore(8)
+
+	if _, err := store.CheckAndRecord("key-1", time.Nanosecond); err != nil {
+		t.Fatalf("CheckAndRecord: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	seen, err := store.CheckAndRecord("key-1", time.Hour)
+	if err != nil {
+		t.Fatalf("CheckAndRecord: %v", err)
+	}
+	if seen {
+		t.Fatalf("CheckAndRecord reported an expired key as still seen")
+	}
+}
+
+// TestLRUIdempotencyStoreCheckAndRecordConcurrent exercises the property
+// CheckAndRecord exists to guarantee: of many concurrent callers racing on
+// the same key, exactly one observes alreadySeen == false.
+func TestLRUIdempotencyStoreCheckAndRecordConcurrent(t *testing.T) {
+	store := NewLRUIdempotencyStore(8)
+
+	const attempts = 64
+	var firstCount int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen, err := store.CheckAndRecord("shared-key", time.Hour)
+			if err != nil {
+				t.Errorf("CheckAndRecord: %v", err)
+				return
+			}
+			if !seen {
+				mu.Lock()
+				firstCount++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstCount != 1 {
+		t.Fatalf("got %d callers observing alreadySeen == false, want exactly 1", firstCount)
+	}
+}
@@ -0,0 +1,278 @@
+package webhooks
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is a single notification recorded in a Journal, replayed in order to
+// subscribers that register with Registry.SubscribeFrom.
+type Entry struct {
+	// Seq is monotonically increasing and unique within a Journal.
+	Seq uint64
+
+	// Raw is the original webhook body, preserved so it can be re-parsed
+	// with Parse after notification structs change.
+	Raw []byte
+
+	// Notification is the parsed form of Raw. RingJournal returns the
+	// value passed to Append; FileJournal re-parses Raw each time it
+	// reads the entry back.
+	Notification interface{}
+
+	ReceivedAt time.Time
+}
+
+// Journal is an append-only log of verified notifications. A Handler
+// configured with WithJournal appends to it before fanning a notification
+// out to subscribers, so a new subscriber can catch up on history via
+// Registry.SubscribeFrom before joining the live stream.
+type Journal interface {
+	// Append records a notification and returns its sequence number.
+	Append(raw []byte, parsed interface{}, receivedAt time.Time) (seq uint64, err error)
+
+	// Range calls fn, in order, for every entry with Seq >= from. Range
+	// stops and returns fn's error as soon as fn returns one.
+	Range(from uint64, fn func(Entry) error) error
+}
+
+// defaultRingJournalCapacity is the number of entries a RingJournal retains
+// when none is given to NewRingJournal.
+const defaultRingJournalCapacity = 1024
+
+// RingJournal is an in-memory Journal that retains only the most recent
+// entries, discarding older ones once it reaches capacity. It does not
+// survive a process restart; use FileJournal when replay needs to outlive
+// the process.
+type RingJournal struct {
+	mu       sync.Mutex
+	capacity int
+	nextSeq  uint64
+	entries  []Entry
+}
+
+// NewRingJournal creates a RingJournal retaining at most capacity entries.
+// A capacity <= 0 uses defaultRingJournalCapacity.
+func NewRingJournal(capacity int) *RingJournal {
+	if capacity <= 0 {
+		capacity = defaultRingJournalCapacity
+	}
+	return &RingJournal{capacity: capacity}
+}
+
+// Append implements Journal.
+func (j *RingJournal) Append(raw []byte, parsed interface{}, receivedAt time.Time) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.nextSeq++
+	j.entries = append(j.entries, Entry{
+		Seq:          j.nextSeq,
+		Raw:          raw,
+		Notification: parsed,
+		ReceivedAt:   receivedAt,
+	})
+
+	if len(j.entries) > j.capacity {
+		j.entries = j.entries[len(j.entries)-j.capacity:]
+	}
+
+	return j.nextSeq, nil
+}
+
+// Range implements Journal. Entries evicted by capacity are silently
+// skipped, so callers that need from to be satisfiable exactly should size
+// the ring generously or use FileJournal instead.
+func (j *RingJournal) Range(from uint64, fn func(Entry) error) error {
+	j.mu.Lock()
+	entries := make([]Entry, len(j.entries))
+	copy(entries, j.entries)
+	j.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.Seq < from {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexRecordSize is the fixed size, in bytes, of each record in a
+// FileJournal's index file: seq (8) + data offset (8) + length (4) +
+// receivedAt as UnixNano (8).
+const indexRecordSize = 8 + 8 + 4 + 8
+
+// FileJournal is a Journal backed by two files: an append-only data file
+// holding each entry's raw, length-prefixed webhook body, and a fixed-width
+// index file used to look up a given entry's offset and length in the data
+// file without re-parsing everything that precedes it.
+type FileJournal struct {
+	mu   sync.Mutex
+	data *os.File
+	idx  *os.File
+
+	nextSeq  uint64
+	dataSize int64
+}
+
+// OpenFileJournal opens (creating if necessary) a FileJournal backed by
+// journal.dat and journal.idx in dir.
+func OpenFileJournal(dir string) (*FileJournal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	data, err := os.OpenFile(filepath.Join(dir, "journal.dat"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := os.OpenFile(filepath.Join(dir, "journal.idx"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+
+	dataInfo, err := data.Stat()
+	if err != nil {
+		data.Close()
+		idx.Close()
+		return nil, err
+	}
+
+	idxInfo, err := idx.Stat()
+	if err != nil {
+		data.Close()
+		idx.Close()
+		return nil, err
+	}
+
+	return &FileJournal{
+		data:     data,
+		idx:      idx,
+		nextSeq:  uint64(idxInfo.Size() / indexRecordSize),
+		dataSize: dataInfo.Size(),
+	}, nil
+}
+
+// Append implements Journal.
+func (j *FileJournal) Append(raw []byte, parsed interface{}, receivedAt time.Time) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	offset := j.dataSize
+
+	buf := make([]byte, 4+len(raw))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(raw)))
+	copy(buf[4:], raw)
+
+	// Track dataSize from the number of bytes actually written, even on
+	// a short write or error, so a later successful Append still lands
+	// at the true end of the file instead of overlapping a partial
+	// record left behind by this one.
+	n, err := j.data.Write(buf)
+	j.dataSize += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	if n != len(buf) {
+		return 0, io.ErrShortWrite
+	}
+
+	j.nextSeq++
+	seq := j.nextSeq
+
+	var record [indexRecordSize]byte
+	binary.BigEndian.PutUint64(record[0:8], seq)
+	binary.BigEndian.PutUint64(record[8:16], uint64(offset))
+	binary.BigEndian.PutUint32(record[16:20], uint32(len(raw)))
+	binary.BigEndian.PutUint64(record[20:28], uint64(receivedAt.UnixNano()))
+	if _, err := j.idx.Write(record[:]); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// Range implements Journal. It only holds the journal's lock long enough to
+// read the matching raw records; fn is invoked after the lock is released,
+// so a slow or blocking fn (e.g. feeding a Subscription.Events channel)
+// never stalls concurrent Append calls.
+func (j *FileJournal) Range(from uint64, fn func(Entry) error) error {
+	entries, err := j.readEntries(from)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *FileJournal) readEntries(from uint64) ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.idx.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	defer j.idx.Seek(0, io.SeekEnd)
+
+	index, err := ioutil.ReadAll(j.idx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for i := 0; i+indexRecordSize <= len(index); i += indexRecordSize {
+		record := index[i : i+indexRecordSize]
+
+		seq := binary.BigEndian.Uint64(record[0:8])
+		if seq < from {
+			continue
+		}
+		offset := int64(binary.BigEndian.Uint64(record[8:16]))
+		length := binary.BigEndian.Uint32(record[16:20])
+		receivedAt := time.Unix(0, int64(binary.BigEndian.Uint64(record[20:28])))
+
+		raw := make([]byte, length)
+		if _, err := j.data.ReadAt(raw, offset+4); err != nil {
+			return nil, err
+		}
+
+		notification, _, err := parse(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry{Seq: seq, Raw: raw, Notification: notification, ReceivedAt: receivedAt})
+	}
+
+	return entries, nil
+}
+
+// Close closes the underlying data and index files.
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	idxErr := j.idx.Close()
+	dataErr := j.data.Close()
+	if idxErr != nil {
+		return idxErr
+	}
+	return dataErr
+}
@@ -0,0 +1,160 @@
+package webhooks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func accountNotificationBody(accountCode string) []byte {
+	return []byte(fmt.Sprintf(
+		`<%s><account><account_code>%s</account_code></account></%s>`,
+		NewAccount, accountCode, NewAccount,
+	))
+}
+
+func testJournalRoundTrip(t *testing.T, j Journal) {
+	t.Helper()
+
+	now := time.Now()
+	var seqs []uint64
+	for i := 0; i < 3; i++ {
+		seq, err := j.Append(accountNotificationBody(fmt.Sprintf("acct-%d", i)), &NewAccountNotification{}, now)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		seqs = append(seqs, seq)
+	}
+
+	if seqs[0] == 0 || seqs[1] != seqs[0]+1 || seqs[2] != seqs[1]+1 {
+		t.Fatalf("Append returned non-monotonic sequence numbers: %v", seqs)
+	}
+
+	var all []Entry
+	if err := j.Range(0, func(e Entry) error {
+		all = append(all, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+
+	if len(all) != 3 {
+		t.Fatalf("Range(0, ...) visited %d entries, want 3", len(all))
+	}
+	for i, e := range all {
+		if e.Seq != seqs[i] {
+			t.Fatalf("entry %d has Seq %d, want %d", i, e.Seq, seqs[i])
+		}
+		if _, ok := e.Notification.(*NewAccountNotification); !ok {
+			t.Fatalf("entry %d Notification is %T, want *NewAccountNotification", i, e.Notification)
+		}
+	}
+
+	var fromMiddle []Entry
+	if err := j.Range(seqs[1], func(e Entry) error {
+		fromMiddle = append(fromMiddle, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if len(fromMiddle) != 2 {
+		t.Fatalf("Range(%d, ...) visited %d entries, want 2", seqs[1], len(fromMiddle))
+	}
+	if fromMiddle[0].Seq != seqs[1] {
+		t.Fatalf("Range(%d, ...) first entry has Seq %d, want %d", seqs[1], fromMiddle[0].Seq, seqs[1])
+	}
+
+	stopErr := fmt.Errorf("stop")
+	visited := 0
+	err := j.Range(0, func(e Entry) error {
+		visited++
+		return stopErr
+	})
+	if err != stopErr {
+		t.Fatalf("Range returned %v, want the error fn returned", err)
+	}
+	if visited != 1 {
+		t.Fatalf("Range invoked fn %d times after it returned an error, want 1", visited)
+	}
+}
+
+func TestRingJournalRoundTrip(t *testing.T) {
+	testJournalRoundTrip(t, NewRingJournal(0))
+}
+
+func TestRingJournalEvictsOldestPastCapacity(t *testing.T) {
+	j := NewRingJournal(2)
+
+	for i := 0; i < 3; i++ {
+		if _, err := j.Append(accountNotificationBody(fmt.Sprintf("acct-%d", i)), &NewAccountNotification{}, time.Now()); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	var seqs []uint64
+	if err := j.Range(0, func(e Entry) error {
+		seqs = append(seqs, e.Seq)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+
+	if len(seqs) != 2 {
+		t.Fatalf("Range(0, ...) visited %d entries, want 2 (capacity)", len(seqs))
+	}
+	if seqs[0] != 2 || seqs[1] != 3 {
+		t.Fatalf("Range(0, ...) visited seqs %v, want [2 3] (the oldest entry evicted)", seqs)
+	}
+}
+
+func TestFileJournalRoundTrip(t *testing.T) {
+	j, err := OpenFileJournal(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenFileJournal: %v", err)
+	}
+	defer j.Close()
+
+	testJournalRoundTrip(t, j)
+}
+
+func TestFileJournalSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	j, err := OpenFileJournal(dir)
+	if err != nil {
+		t.Fatalf("OpenFileJournal: %v", err)
+	}
+
+	seq, err := j.Append(accountNotificationBody("acct-0"), &NewAccountNotification{}, time.Now())
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := OpenFileJournal(dir)
+	if err != nil {
+		t.Fatalf("OpenFileJournal (reopen): %v", err)
+	}
+	defer reopened.Close()
+
+	nextSeq, err := reopened.Append(accountNotificationBody("acct-1"), &NewAccountNotification{}, time.Now())
+	if err != nil {
+		t.Fatalf("Append (after reopen): %v", err)
+	}
+	if nextSeq != seq+1 {
+		t.Fatalf("Append after reopen returned seq %d, want %d", nextSeq, seq+1)
+	}
+
+	var all []Entry
+	if err := reopened.Range(0, func(e Entry) error {
+		all = append(all, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Range(0, ...) visited %d entries after reopen, want 2", len(all))
+	}
+}
@@ -0,0 +1,233 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/blacklightcms/recurly"
+)
+
+// defaultReconcileInterval is how often a Reconciler polls the REST API for
+// each resource when no interval is configured.
+const defaultReconcileInterval = 5 * time.Minute
+
+// CursorStore persists, per event type, the timestamp of the most recently
+// reconciled record. It lets a Reconciler resume from where it left off
+// across restarts instead of re-walking the full resource history.
+type CursorStore interface {
+	LastSeen(eventType string) (time.Time, error)
+	SaveCursor(eventType string, seen time.Time) error
+}
+
+// reconciledEvent is a notification synthesized from a REST resource rather
+// than parsed off an incoming webhook body.
+type reconciledEvent struct {
+	eventType    string
+	notification interface{}
+}
+
+// reconcileFunc pages through one REST resource for records matching since,
+// synthesizing the same notification structs Parse would have produced from
+// the equivalent webhook. It returns the time the caller should advance the
+// resource's cursor to, which is the moment the fetch started rather than
+// any per-record timestamp: every record changed between since and that
+// moment is guaranteed to have been included in the page(s) just fetched.
+type reconcileFunc func(ctx context.Context, client *recurly.Client, since time.Time) ([]reconciledEvent, time.Time, error)
+
+// reconcilableResources lists the REST resources a Reconciler walks on every
+// tick, keyed by the event type used to track their cursor.
+var reconcilableResources = []struct {
+	eventType string
+	fetch     reconcileFunc
+}{
+	{eventType: SuccessfulPayment, fetch: reconcileTransactions},
+	{eventType: UpdatedSubscription, fetch: reconcileSubscriptions},
+	{eventType: PastDueInvoice, fetch: reconcileDunningInvoices},
+}
+
+// Reconciler periodically reconciles missed webhook deliveries by pulling
+// recently changed resources from the Recurly REST API and replaying them
+// through a Registry as if they had arrived as webhooks, similar in spirit
+// to how lnd's invoice registry combines a live subscription feed with
+// queued historical events for late subscribers.
+type Reconciler struct {
+	client   *recurly.Client
+	cursors  CursorStore
+	registry *Registry
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ReconcilerOption configures a Reconciler constructed with NewReconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithReconcileInterval overrides defaultReconcileInterval.
+func WithReconcileInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.interval = d
+	}
+}
+
+// NewReconciler creates a Reconciler. Call Start to begin polling.
+func NewReconciler(client *recurly.Client, cursors CursorStore, registry *Registry, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client:   client,
+		cursors:  cursors,
+		registry: registry,
+		interval: defaultReconcileInterval,
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Start begins polling the REST API on the configured interval.
+func (r *Reconciler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go r.run(ctx)
+}
+
+// Stop halts polling and waits for the in-flight tick, if any, to finish.
+func (r *Reconciler) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *Reconciler) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.reconcileOnce(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcileOnce walks every resource in reconcilableResources once,
+// publishing any synthesized notifications and advancing each resource's
+// cursor. A resource that errors is left at its prior cursor and retried on
+// the next tick.
+func (r *Reconciler) reconcileOnce(ctx context.Context) {
+	for _, res := range reconcilableResources {
+		since, err := r.cursors.LastSeen(res.eventType)
+		if err != nil {
+			continue
+		}
+
+		events, latest, err := res.fetch(ctx, r.client, since)
+		if err != nil {
+			continue
+		}
+
+		for _, ev := range events {
+			r.registry.publish(ev.eventType, ev.notification)
+		}
+
+		if latest.After(since) {
+			r.cursors.SaveCursor(res.eventType, latest)
+		}
+	}
+}
+
+// reconcileTransactions pages through transactions updated since since,
+// synthesizing SuccessfulPaymentNotification, FailedPaymentNotification, or
+// VoidPaymentNotification values depending on each transaction's status.
+func reconcileTransactions(ctx context.Context, client *recurly.Client, since time.Time) ([]reconciledEvent, time.Time, error) {
+	fetchedAt := time.Now()
+
+	pager := client.Transactions.List(&recurly.PagerOptions{BeginTime: recurly.NewTime(since)})
+
+	var transactions []recurly.Transaction
+	if err := pager.FetchAll(ctx, &transactions); err != nil {
+		return nil, since, err
+	}
+
+	events := make([]reconciledEvent, 0, len(transactions))
+	for _, txn := range transactions {
+		eventType, notification := transactionNotification(txn)
+		events = append(events, reconciledEvent{eventType: eventType, notification: notification})
+	}
+
+	return events, fetchedAt, nil
+}
+
+// transactionNotification maps a transaction's status to the notification
+// type a webhook would have delivered for it.
+func transactionNotification(txn recurly.Transaction) (eventType string, notification interface{}) {
+	switch txn.Status {
+	case recurly.TransactionStatusVoid:
+		return VoidPayment, &VoidPaymentNotification{Account: txn.Account, Transaction: txn}
+	case recurly.TransactionStatusFailed:
+		return FailedPayment, &FailedPaymentNotification{Account: txn.Account, Transaction: txn}
+	default:
+		return SuccessfulPayment, &SuccessfulPaymentNotification{Account: txn.Account, Transaction: txn}
+	}
+}
+
+// reconcileSubscriptions pages through subscriptions changed since since,
+// synthesizing UpdatedSubscriptionNotification values.
+func reconcileSubscriptions(ctx context.Context, client *recurly.Client, since time.Time) ([]reconciledEvent, time.Time, error) {
+	fetchedAt := time.Now()
+
+	pager := client.Subscriptions.List(&recurly.PagerOptions{BeginTime: recurly.NewTime(since)})
+
+	var subscriptions []recurly.Subscription
+	if err := pager.FetchAll(ctx, &subscriptions); err != nil {
+		return nil, since, err
+	}
+
+	events := make([]reconciledEvent, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		events = append(events, reconciledEvent{
+			eventType: UpdatedSubscription,
+			notification: &UpdatedSubscriptionNotification{
+				Account:      recurly.Account{Code: sub.AccountCode},
+				Subscription: sub,
+			},
+		})
+	}
+
+	return events, fetchedAt, nil
+}
+
+// reconcileDunningInvoices pages through invoices currently in dunning,
+// synthesizing PastDueInvoiceNotification values.
+func reconcileDunningInvoices(ctx context.Context, client *recurly.Client, since time.Time) ([]reconciledEvent, time.Time, error) {
+	fetchedAt := time.Now()
+
+	pager := client.Invoices.List(&recurly.PagerOptions{
+		State:     recurly.ChargeInvoiceStatePastDue,
+		BeginTime: recurly.NewTime(since),
+	})
+
+	var invoices []recurly.Invoice
+	if err := pager.FetchAll(ctx, &invoices); err != nil {
+		return nil, since, err
+	}
+
+	events := make([]reconciledEvent, 0, len(invoices))
+	for _, inv := range invoices {
+		events = append(events, reconciledEvent{
+			eventType: PastDueInvoice,
+			notification: &PastDueInvoiceNotification{
+				Account: recurly.Account{Code: inv.AccountCode},
+				Invoice: inv,
+			},
+		})
+	}
+
+	return events, fetchedAt, nil
+}
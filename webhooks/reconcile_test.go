@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/blacklightcms/recurly"
+)
+
+func TestTransactionNotification(t *testing.T) {
+	account := recurly.Account{Code: "acct-1"}
+
+	tests := []struct {
+		status    string
+		wantEvent string
+		wantType  interface{}
+	}{
+		{status: recurly.TransactionStatusSuccess, wantEvent: SuccessfulPayment, wantType: &SuccessfulPaymentNotification{}},
+		{status: recurly.TransactionStatusFailed, wantEvent: FailedPayment, wantType: &FailedPaymentNotification{}},
+		{status: recurly.TransactionStatusVoid, wantEvent: VoidPayment, wantType: &VoidPaymentNotification{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			txn := recurly.Transaction{Account: account, Status: tt.status}
+
+			gotEvent, gotNotification := transactionNotification(txn)
+			if gotEvent != tt.wantEvent {
+				t.Fatalf("eventType = %q, want %q", gotEvent, tt.wantEvent)
+			}
+
+			gotType := fmt.Sprintf("%T", gotNotification)
+			wantType := fmt.Sprintf("%T", tt.wantType)
+			if gotType != wantType {
+				t.Fatalf("notification type = %s, want %s", gotType, wantType)
+			}
+		})
+	}
+}
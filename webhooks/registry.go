@@ -0,0 +1,328 @@
+package webhooks
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+// defaultSubscriptionBuffer is the default number of notifications a
+// Subscription's channel will buffer before the registry starts treating it
+// as a slow consumer.
+const defaultSubscriptionBuffer = 64
+
+// errRegistryStopped is returned internally by SubscribeFrom's replay
+// goroutine when the registry is stopped mid-replay; it never escapes to a
+// caller.
+var errRegistryStopped = errors.New("webhooks: registry stopped")
+
+// replayEntry is sent to the dispatch loop by SubscribeFrom's replay
+// goroutine. It is routed through the loop, rather than written to the
+// subscription's Events channel directly, so the dispatch loop remains the
+// Events channel's sole writer and closer; a goroutine outside the loop
+// sending on (or racing a concurrent close of) a subscription's Events
+// channel is exactly the race this avoids.
+type replayEntry struct {
+	subID        uint32
+	notification interface{}
+}
+
+// Subscription is a handle returned by Registry.Subscribe and
+// Registry.SubscribeAll. Notifications matching the subscription are
+// delivered on Events until Cancel is called, at which point Events is
+// closed.
+type Subscription struct {
+	// Events delivers notifications matching the subscription's event
+	// type. It is buffered and closed when the subscription is canceled.
+	Events chan interface{}
+
+	id        uint32
+	eventType string
+	registry  *Registry
+}
+
+// Cancel unregisters the subscription from its registry and closes Events.
+// It is safe to call Cancel more than once and from any goroutine.
+func (s *Subscription) Cancel() {
+	select {
+	case s.registry.subscriptionCancels <- s.id:
+	case <-s.registry.quit:
+	}
+}
+
+// subscribeRequest is sent to the registry's dispatch loop to register a new
+// subscription and receive its handle back.
+type subscribeRequest struct {
+	eventType string
+	resp      chan *Subscription
+}
+
+// parsedNotification pairs a notification with the event type it was parsed
+// as, so the dispatch loop can match it against subscriptions without having
+// to re-derive the type from the notification's concrete Go type.
+type parsedNotification struct {
+	eventType    string
+	notification interface{}
+}
+
+// Registry fans out webhook notifications parsed by Dispatch to any number
+// of independent subscribers, each interested in a single event type or all
+// of them. It is modeled on lnd's InvoiceRegistry: a single goroutine owns
+// the subscriber map and is only ever touched through channels, so no
+// locking is required.
+type Registry struct {
+	bufferSize   int
+	blockOnSlow  bool
+	nextClientID uint32
+	journal      Journal
+
+	newSubscriptions    chan *subscribeRequest
+	subscriptionCancels chan uint32
+	notifications       chan *parsedNotification
+	replays             chan *replayEntry
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// RegistryOption configures a Registry constructed with NewRegistry.
+type RegistryOption func(*Registry)
+
+// WithSubscriptionBuffer overrides the default buffer size used for each
+// subscriber's Events channel.
+func WithSubscriptionBuffer(n int) RegistryOption {
+	return func(r *Registry) {
+		r.bufferSize = n
+	}
+}
+
+// WithBlockOnSlowConsumers makes the dispatch loop block until a slow
+// subscriber drains its Events channel instead of dropping the
+// notification. While it is blocked, further notifications queue up to
+// defaultSubscriptionBuffer deep, and Dispatch blocks once that queue is
+// full. The default is to drop, so one stalled subscriber can never stall
+// delivery to the rest.
+func WithBlockOnSlowConsumers(block bool) RegistryOption {
+	return func(r *Registry) {
+		r.blockOnSlow = block
+	}
+}
+
+// WithReplayJournal gives the registry a Journal to replay from when a
+// subscriber registers with SubscribeFrom. It should be the same Journal a
+// Handler was configured with via WithJournal, so the registry replays
+// exactly what was durably recorded before fan-out.
+func WithReplayJournal(j Journal) RegistryOption {
+	return func(r *Registry) {
+		r.journal = j
+	}
+}
+
+// NewRegistry creates a Registry and starts its dispatch loop.
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{
+		bufferSize:          defaultSubscriptionBuffer,
+		newSubscriptions:    make(chan *subscribeRequest),
+		subscriptionCancels: make(chan uint32),
+		notifications:       make(chan *parsedNotification, defaultSubscriptionBuffer),
+		replays:             make(chan *replayEntry),
+		quit:                make(chan struct{}),
+		done:                make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	go r.dispatchLoop()
+
+	return r
+}
+
+// Stop shuts down the registry's dispatch loop and closes every outstanding
+// subscription's Events channel.
+func (r *Registry) Stop() {
+	close(r.quit)
+	<-r.done
+}
+
+// Subscribe registers interest in a single event type (one of the constants
+// in this package, e.g. NewSubscription or FailedPayment) and returns a
+// handle whose Events channel will receive matching notifications.
+func (r *Registry) Subscribe(eventType string) *Subscription {
+	return r.subscribe(eventType)
+}
+
+// SubscribeAll registers interest in every event type.
+func (r *Registry) SubscribeAll() *Subscription {
+	return r.subscribe("")
+}
+
+// SubscribeFrom registers interest in every event type and, if the registry
+// was given a Journal via WithReplayJournal, replays every journaled entry
+// with a sequence number >= from onto the returned Subscription's Events
+// channel. Replay runs concurrently with live delivery, which starts as soon
+// as the subscription is registered: live notifications may arrive before,
+// between, or after replayed ones, and a notification journaled while replay
+// is in progress may arrive twice. Events carries only the notification
+// values, not their Entry.Seq. Replayed entries follow the same slow-consumer
+// policy as live ones, so unless WithBlockOnSlowConsumers(true) is set, an
+// entry that finds Events full is dropped.
+func (r *Registry) SubscribeFrom(from uint64) *Subscription {
+	sub := r.SubscribeAll()
+	if sub == nil || r.journal == nil {
+		return sub
+	}
+
+	go func() {
+		r.journal.Range(from, func(e Entry) error {
+			select {
+			case r.replays <- &replayEntry{subID: sub.id, notification: e.Notification}:
+				return nil
+			case <-r.quit:
+				return errRegistryStopped
+			}
+		})
+	}()
+
+	return sub
+}
+
+func (r *Registry) subscribe(eventType string) *Subscription {
+	req := &subscribeRequest{
+		eventType: eventType,
+		resp:      make(chan *Subscription, 1),
+	}
+
+	select {
+	case r.newSubscriptions <- req:
+	case <-r.quit:
+		return nil
+	}
+
+	select {
+	case sub := <-req.resp:
+		return sub
+	case <-r.quit:
+		return nil
+	}
+}
+
+// Dispatch parses an incoming webhook body with Parse and fans the resulting
+// notification out to any matching subscriptions. It returns the parsed
+// notification so it can also be used directly as the http.Handler entry
+// point's underlying primitive.
+func (r *Registry) Dispatch(body io.Reader) (interface{}, error) {
+	if closer, ok := body.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	dst, eventType, err := parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	r.publish(eventType, dst)
+
+	return dst, nil
+}
+
+// publish hands a parsed notification to the dispatch loop for fan-out. It
+// is used by both Dispatch and Handler, the latter publishing only after
+// auth and idempotency checks have passed.
+func (r *Registry) publish(eventType string, n interface{}) {
+	select {
+	case r.notifications <- &parsedNotification{eventType: eventType, notification: n}:
+	case <-r.quit:
+	}
+}
+
+// deliver sends n on sub.Events, blocking if the registry was configured
+// with WithBlockOnSlowConsumers(true) and dropping otherwise. It reports
+// whether the dispatch loop should keep running; it returns false only when
+// the registry was stopped while blocked delivering to a slow consumer.
+func (r *Registry) deliver(sub *Subscription, n interface{}) bool {
+	if r.blockOnSlow {
+		select {
+		case sub.Events <- n:
+		case <-r.quit:
+			return false
+		}
+		return true
+	}
+
+	select {
+	case sub.Events <- n:
+	default:
+		// Slow consumer; drop rather than block the rest of the
+		// fan-out.
+	}
+	return true
+}
+
+func (r *Registry) dispatchLoop() {
+	defer close(r.done)
+
+	subs := make(map[uint32]*Subscription)
+
+	// However the loop exits, including when it is stopped while blocked
+	// delivering to a slow consumer, close every outstanding Events.
+	defer func() {
+		for id, sub := range subs {
+			delete(subs, id)
+			close(sub.Events)
+		}
+	}()
+
+	for {
+		select {
+		case req := <-r.newSubscriptions:
+			id := atomic.AddUint32(&r.nextClientID, 1)
+			sub := &Subscription{
+				Events:    make(chan interface{}, r.bufferSize),
+				id:        id,
+				eventType: req.eventType,
+				registry:  r,
+			}
+			subs[id] = sub
+			req.resp <- sub
+
+		case id := <-r.subscriptionCancels:
+			if sub, ok := subs[id]; ok {
+				delete(subs, id)
+				close(sub.Events)
+			}
+
+		case n := <-r.notifications:
+			for _, sub := range subs {
+				if sub.eventType != "" && sub.eventType != n.eventType {
+					continue
+				}
+				if !r.deliver(sub, n.notification) {
+					return
+				}
+			}
+
+		case rp := <-r.replays:
+			// The subscription may already have been canceled and
+			// removed from subs; a replay entry for it is simply
+			// dropped, since the dispatch loop is the only writer
+			// and closer of Events, so there's nothing unsafe about
+			// that.
+			if sub, ok := subs[rp.subID]; ok {
+				if !r.deliver(sub, rp.notification) {
+					return
+				}
+			}
+
+		case <-r.quit:
+			return
+		}
+	}
+}
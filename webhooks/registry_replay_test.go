@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistrySubscribeFromReplaysJournal(t *testing.T) {
+	j := NewRingJournal(0)
+	for i := 0; i < 3; i++ {
+		if _, err := j.Append(accountNotificationBody("acct"), &NewAccountNotification{}, time.Now()); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	r := NewRegistry(WithReplayJournal(j))
+	defer r.Stop()
+
+	sub := r.SubscribeFrom(0)
+	defer sub.Cancel()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case _, ok := <-sub.Events:
+			if !ok {
+				t.Fatalf("Events closed after %d replayed entries, want 3", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for replayed entry %d", i)
+		}
+	}
+}
+
+// TestRegistrySubscribeFromCancelDuringReplay exercises the race
+// SubscribeFrom used to paper over with recover(): canceling a subscription
+// while its replay is still in progress must not panic or deadlock, since
+// the dispatch loop is now the only goroutine that ever writes to or closes
+// Events.
+func TestRegistrySubscribeFromCancelDuringReplay(t *testing.T) {
+	j := NewRingJournal(0)
+	for i := 0; i < 200; i++ {
+		if _, err := j.Append(accountNotificationBody("acct"), &NewAccountNotification{}, time.Now()); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	r := NewRegistry(WithReplayJournal(j))
+	defer r.Stop()
+
+	sub := r.SubscribeFrom(0)
+
+	// Don't drain Events; cancel immediately so the replay goroutine is
+	// still blocked trying to hand entries to the dispatch loop when
+	// Cancel runs.
+	sub.Cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range sub.Events {
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for Events to close after Cancel during replay")
+	}
+}
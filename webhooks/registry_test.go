@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistryFanOut(t *testing.T) {
+	r := NewRegistry()
+	defer r.Stop()
+
+	all := r.SubscribeAll()
+	defer all.Cancel()
+
+	payments := r.Subscribe(SuccessfulPayment)
+	defer payments.Cancel()
+
+	accounts := r.Subscribe(NewAccount)
+	defer accounts.Cancel()
+
+	r.publish(SuccessfulPayment, "payment-1")
+
+	select {
+	case got := <-all.Events:
+		if got != "payment-1" {
+			t.Fatalf("all.Events got %v, want payment-1", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for all-events subscriber")
+	}
+
+	select {
+	case got := <-payments.Events:
+		if got != "payment-1" {
+			t.Fatalf("payments.Events got %v, want payment-1", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for matching subscriber")
+	}
+
+	select {
+	case got := <-accounts.Events:
+		t.Fatalf("accounts.Events unexpectedly received %v", got)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestRegistryCancelClosesEvents(t *testing.T) {
+	r := NewRegistry()
+	defer r.Stop()
+
+	sub := r.SubscribeAll()
+	sub.Cancel()
+
+	select {
+	case _, ok := <-sub.Events:
+		if ok {
+			t.Fatal("Events delivered a value after Cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Events to close after Cancel")
+	}
+}
+
+func TestRegistryCancelIsIdempotent(t *testing.T) {
+	r := NewRegistry()
+	defer r.Stop()
+
+	sub := r.SubscribeAll()
+	sub.Cancel()
+	sub.Cancel()
+}
+
+func TestRegistryStopClosesSubscriptions(t *testing.T) {
+	r := NewRegistry()
+
+	sub := r.SubscribeAll()
+	r.Stop()
+
+	select {
+	case _, ok := <-sub.Events:
+		if ok {
+			t.Fatal("Events delivered a value after Stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Events to close after Stop")
+	}
+}
@@ -14,12 +14,15 @@ const (
 	NewAccount         = "new_account_notification"
 	CanceledAccount    = "cancelled_account_notification"
 	ReactivatedAccount = "reactivated_account_notification"
+	UpdatedAccount     = "updated_account_notification"
+	UpdatedBillingInfo = "updated_billing_info_notification"
 
-	NewSubscription      = "new_subscription_notification"
-	UpdatedSubscription  = "updated_subscription_notification"
-	CanceledSubscription = "canceled_subscription_notification"
-	ExpiredSubscription  = "expired_subscription_notification"
-	RenewedSubscription  = "renewed_subscription_notification"
+	NewSubscription       = "new_subscription_notification"
+	UpdatedSubscription   = "updated_subscription_notification"
+	CanceledSubscription  = "canceled_subscription_notification"
+	ExpiredSubscription   = "expired_subscription_notification"
+	RenewedSubscription   = "renewed_subscription_notification"
+	ScheduledSubscription = "scheduled_subscription_change_notification"
 
 	NewInvoice        = "new_invoice_notification"
 	ProcessingInvoice = "processing_invoice_notification"
@@ -28,12 +31,34 @@ const (
 
 	SuccessfulPayment = "successful_payment_notification"
 	FailedPayment     = "failed_payment_notification"
+	VoidPayment       = "void_payment_notification"
+	SuccessfulRefund  = "successful_refund_notification"
+	NewCreditPayment  = "new_credit_payment_notification"
+
+	NewDunningEvent = "new_dunning_event_notification"
+
+	PurchasedGiftCard = "purchased_gift_card_notification"
 )
 
 type notificationName struct {
 	XMLName xml.Name
 }
 
+// GiftCard describes the gift card embedded in a
+// PurchasedGiftCardNotification. The recurly client package has no
+// equivalent type since gift cards aren't exposed through the REST API it
+// wraps, so this is modeled directly off the webhook payload.
+type GiftCard struct {
+	ProductCode          string           `xml:"product_code"`
+	UnitAmountInCents    int              `xml:"unit_amount_in_cents"`
+	Currency             string           `xml:"currency"`
+	GifterAccountCode    string           `xml:"gifter_account_code"`
+	RecipientAccountCode string           `xml:"recipient_account_code"`
+	RedemptionCode       string           `xml:"redemption_code"`
+	Delivered            bool             `xml:"delivered"`
+	DeliveredAt          recurly.NullTime `xml:"delivered_at"`
+}
+
 type (
 	// NewAccountNotification is sent when a new account is created
 	NewAccountNotification struct {
@@ -50,6 +75,16 @@ type (
 		Account recurly.Account `xml:"account"`
 	}
 
+	// UpdatedAccountNotification is sent when an account's details are updated.
+	UpdatedAccountNotification struct {
+		Account recurly.Account `xml:"account"`
+	}
+
+	// UpdatedBillingInfoNotification is sent when an account's billing info is updated.
+	UpdatedBillingInfoNotification struct {
+		Account recurly.Account `xml:"account"`
+	}
+
 	// NewSubscriptionNotification is sent when a new subscription is created
 	NewSubscriptionNotification struct {
 		Account      recurly.Account      `xml:"account"`
@@ -80,6 +115,13 @@ type (
 		Subscription recurly.Subscription `xml:"subscription"`
 	}
 
+	// ScheduledPaymentNotification is sent when a subscription change is
+	// scheduled to take effect at the end of the current billing cycle.
+	ScheduledPaymentNotification struct {
+		Account      recurly.Account      `xml:"account"`
+		Subscription recurly.Subscription `xml:"subscription"`
+	}
+
 	// SuccessfulPaymentNotification is sent when a payment is successful.
 	SuccessfulPaymentNotification struct {
 		Account     recurly.Account     `xml:"account"`
@@ -92,6 +134,39 @@ type (
 		Transaction recurly.Transaction `xml:"transaction"`
 	}
 
+	// VoidPaymentNotification is sent when a payment is voided.
+	VoidPaymentNotification struct {
+		Account     recurly.Account     `xml:"account"`
+		Transaction recurly.Transaction `xml:"transaction"`
+	}
+
+	// SuccessfulRefundNotification is sent when a transaction is refunded
+	// or partially refunded.
+	SuccessfulRefundNotification struct {
+		Account     recurly.Account     `xml:"account"`
+		Transaction recurly.Transaction `xml:"transaction"`
+	}
+
+	// NewCreditPaymentNotification is sent when a credit payment, such as
+	// one generated by a refund, is applied to an account.
+	NewCreditPaymentNotification struct {
+		Account     recurly.Account     `xml:"account"`
+		Transaction recurly.Transaction `xml:"transaction"`
+	}
+
+	// NewDunningEventNotification is sent when an invoice enters a new
+	// step of its dunning campaign.
+	NewDunningEventNotification struct {
+		Account      recurly.Account      `xml:"account"`
+		Subscription recurly.Subscription `xml:"subscription"`
+		Transaction  recurly.Transaction  `xml:"transaction"`
+	}
+
+	// PurchasedGiftCardNotification is sent when a gift card is purchased.
+	PurchasedGiftCardNotification struct {
+		GiftCard GiftCard `xml:"gift_card"`
+	}
+
 	// NewInvoiceNotification is sent when a new invoice is generated.
 	NewInvoiceNotification struct {
 		Account recurly.Account `xml:"account"`
@@ -135,6 +210,26 @@ func (n *FailedPaymentNotification) setTransactionFields(id string, invoiceNumbe
 	n.Transaction.InvoiceNumber = invoiceNumber
 }
 
+func (n *VoidPaymentNotification) setTransactionFields(id string, invoiceNumber int) {
+	n.Transaction.UUID = id
+	n.Transaction.InvoiceNumber = invoiceNumber
+}
+
+func (n *SuccessfulRefundNotification) setTransactionFields(id string, invoiceNumber int) {
+	n.Transaction.UUID = id
+	n.Transaction.InvoiceNumber = invoiceNumber
+}
+
+func (n *NewCreditPaymentNotification) setTransactionFields(id string, invoiceNumber int) {
+	n.Transaction.UUID = id
+	n.Transaction.InvoiceNumber = invoiceNumber
+}
+
+func (n *NewDunningEventNotification) setTransactionFields(id string, invoiceNumber int) {
+	n.Transaction.UUID = id
+	n.Transaction.InvoiceNumber = invoiceNumber
+}
+
 // transaction allows the transaction id and invoice number to be unmarshalled
 // so they can be set on the notification struct.
 type transaction struct {
@@ -169,9 +264,17 @@ func Parse(r io.Reader) (interface{}, error) {
 		return nil, err
 	}
 
+	dst, _, err := parse(notification)
+	return dst, err
+}
+
+// parse is the shared implementation behind Parse. It additionally returns
+// the notification's XML element name so callers that need to route on the
+// event type (e.g. Registry.Dispatch) don't have to re-parse the payload.
+func parse(notification []byte) (interface{}, string, error) {
 	var n notificationName
 	if err := xml.Unmarshal(notification, &n); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var dst interface{}
@@ -182,16 +285,22 @@ func Parse(r io.Reader) (interface{}, error) {
 		dst = &CanceledAccountNotification{}
 	case ReactivatedAccount:
 		dst = &ReactivatedAccountNotification{}
+	case UpdatedAccount:
+		dst = &UpdatedAccountNotification{}
+	case UpdatedBillingInfo:
+		dst = &UpdatedBillingInfoNotification{}
 	case NewSubscription:
 		dst = &NewSubscriptionNotification{}
 	case UpdatedSubscription:
 		dst = &UpdatedSubscriptionNotification{}
 	case CanceledSubscription:
-		dst = CanceledSubscriptionNotification{}
+		dst = &CanceledSubscriptionNotification{}
 	case ExpiredSubscription:
 		dst = &ExpiredSubscriptionNotification{}
 	case RenewedSubscription:
 		dst = &RenewedSubscriptionNotification{}
+	case ScheduledSubscription:
+		dst = &ScheduledPaymentNotification{}
 	case NewInvoice:
 		dst = &NewInvoiceNotification{}
 	case ProcessingInvoice:
@@ -204,21 +313,31 @@ func Parse(r io.Reader) (interface{}, error) {
 		dst = &SuccessfulPaymentNotification{}
 	case FailedPayment:
 		dst = &FailedPaymentNotification{}
+	case VoidPayment:
+		dst = &VoidPaymentNotification{}
+	case SuccessfulRefund:
+		dst = &SuccessfulRefundNotification{}
+	case NewCreditPayment:
+		dst = &NewCreditPaymentNotification{}
+	case NewDunningEvent:
+		dst = &NewDunningEventNotification{}
+	case PurchasedGiftCard:
+		dst = &PurchasedGiftCardNotification{}
 	default:
-		return nil, ErrUnknownNotification{name: n.XMLName.Local}
+		return nil, "", ErrUnknownNotification{name: n.XMLName.Local}
 	}
 
 	if err := xml.Unmarshal(notification, dst); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if th, ok := dst.(transactionHolder); ok {
 		var t transaction
 		if err := xml.Unmarshal(notification, &t); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		th.setTransactionFields(t.ID, t.InvoiceNumber)
 	}
 
-	return dst, nil
+	return dst, n.XMLName.Local, nil
 }
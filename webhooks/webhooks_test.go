@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseNotificationTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+	}{
+		{
+			name: NewAccount,
+			body: `<` + NewAccount + `><account><account_code>acct-1</account_code></account></` + NewAccount + `>`,
+			want: &NewAccountNotification{},
+		},
+		{
+			name: CanceledAccount,
+			body: `<` + CanceledAccount + `><account><account_code>acct-1</account_code></account></` + CanceledAccount + `>`,
+			want: &CanceledAccountNotification{},
+		},
+		{
+			name: ReactivatedAccount,
+			body: `<` + ReactivatedAccount + `><account><account_code>acct-1</account_code></account></` + ReactivatedAccount + `>`,
+			want: &ReactivatedAccountNotification{},
+		},
+		{
+			name: UpdatedAccount,
+			body: `<` + UpdatedAccount + `><account><account_code>acct-1</account_code></account></` + UpdatedAccount + `>`,
+			want: &UpdatedAccountNotification{},
+		},
+		{
+			name: UpdatedBillingInfo,
+			body: `<` + UpdatedBillingInfo + `><account><account_code>acct-1</account_code></account></` + UpdatedBillingInfo + `>`,
+			want: &UpdatedBillingInfoNotification{},
+		},
+		{
+			name: NewSubscription,
+			body: `<` + NewSubscription + `><account><account_code>acct-1</account_code></account></` + NewSubscription + `>`,
+			want: &NewSubscriptionNotification{},
+		},
+		{
+			name: UpdatedSubscription,
+			body: `<` + UpdatedSubscription + `><account><account_code>acct-1</account_code></account></` + UpdatedSubscription + `>`,
+			want: &UpdatedSubscriptionNotification{},
+		},
+		{
+			name: CanceledSubscription,
+			body: `<` + CanceledSubscription + `><account><account_code>acct-1</account_code></account></` + CanceledSubscription + `>`,
+			want: &CanceledSubscriptionNotification{},
+		},
+		{
+			name: ExpiredSubscription,
+			body: `<` + ExpiredSubscription + `><account><account_code>acct-1</account_code></account></` + ExpiredSubscription + `>`,
+			want: &ExpiredSubscriptionNotification{},
+		},
+		{
+			name: RenewedSubscription,
+			body: `<` + RenewedSubscription + `><account><account_code>acct-1</account_code></account></` + RenewedSubscription + `>`,
+			want: &RenewedSubscriptionNotification{},
+		},
+		{
+			name: ScheduledSubscription,
+			body: `<` + ScheduledSubscription + `><account><account_code>acct-1</account_code></account></` + ScheduledSubscription + `>`,
+			want: &ScheduledPaymentNotification{},
+		},
+		{
+			name: NewInvoice,
+			body: `<` + NewInvoice + `><account><account_code>acct-1</account_code></account></` + NewInvoice + `>`,
+			want: &NewInvoiceNotification{},
+		},
+		{
+			name: ProcessingInvoice,
+			body: `<` + ProcessingInvoice + `><account><account_code>acct-1</account_code></account></` + ProcessingInvoice + `>`,
+			want: &ProcessingInvoiceNotification{},
+		},
+		{
+			name: ClosedInvoice,
+			body: `<` + ClosedInvoice + `><account><account_code>acct-1</account_code></account></` + ClosedInvoice + `>`,
+			want: &ClosedInvoiceNotification{},
+		},
+		{
+			name: PastDueInvoice,
+			body: `<` + PastDueInvoice + `><account><account_code>acct-1</account_code></account></` + PastDueInvoice + `>`,
+			want: &PastDueInvoiceNotification{},
+		},
+		{
+			name: SuccessfulPayment,
+			body: `<` + SuccessfulPayment + `><account><account_code>acct-1</account_code></account><transaction><id>txn-1</id></transaction></` + SuccessfulPayment + `>`,
+			want: &SuccessfulPaymentNotification{},
+		},
+		{
+			name: FailedPayment,
+			body: `<` + FailedPayment + `><account><account_code>acct-1</account_code></account><transaction><id>txn-1</id></transaction></` + FailedPayment + `>`,
+			want: &FailedPaymentNotification{},
+		},
+		{
+			name: VoidPayment,
+			body: `<` + VoidPayment + `><account><account_code>acct-1</account_code></account><transaction><id>txn-1</id></transaction></` + VoidPayment + `>`,
+			want: &VoidPaymentNotification{},
+		},
+		{
+			name: SuccessfulRefund,
+			body: `<` + SuccessfulRefund + `><account><account_code>acct-1</account_code></account><transaction><id>txn-1</id></transaction></` + SuccessfulRefund + `>`,
+			want: &SuccessfulRefundNotification{},
+		},
+		{
+			name: NewCreditPayment,
+			body: `<` + NewCreditPayment + `><account><account_code>acct-1</account_code></account><transaction><id>txn-1</id></transaction></` + NewCreditPayment + `>`,
+			want: &NewCreditPaymentNotification{},
+		},
+		{
+			name: NewDunningEvent,
+			body: `<` + NewDunningEvent + `><account><account_code>acct-1</account_code></account><transaction><id>txn-1</id></transaction></` + NewDunningEvent + `>`,
+			want: &NewDunningEventNotification{},
+		},
+		{
+			name: PurchasedGiftCard,
+			body: `<` + PurchasedGiftCard + `><gift_card><redemption_code>ABC123</redemption_code></gift_card></` + PurchasedGiftCard + `>`,
+			want: &PurchasedGiftCardNotification{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst, eventType, err := parse([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if eventType != tt.name {
+				t.Fatalf("eventType = %q, want %q", eventType, tt.name)
+			}
+
+			gotType := fmt.Sprintf("%T", dst)
+			wantType := fmt.Sprintf("%T", tt.want)
+			if gotType != wantType {
+				t.Fatalf("parse returned %s, want %s", gotType, wantType)
+			}
+		})
+	}
+}
+
+func TestParseUnknownNotification(t *testing.T) {
+	_, _, err := parse([]byte(`<some_unknown_notification></some_unknown_notification>`))
+	if err == nil {
+		t.Fatal("parse returned no error for an unknown notification type")
+	}
+
+	unknown, ok := err.(ErrUnknownNotification)
+	if !ok {
+		t.Fatalf("parse returned error of type %T, want ErrUnknownNotification", err)
+	}
+	if unknown.Name() != "some_unknown_notification" {
+		t.Fatalf("Name() = %q, want %q", unknown.Name(), "some_unknown_notification")
+	}
+}
+
+func TestParseSuccessfulPaymentSetsTransactionFields(t *testing.T) {
+	body := `<` + SuccessfulPayment + `>
+		<account><account_code>acct-1</account_code></account>
+		<transaction><id>txn-1</id><invoice_number>1001</invoice_number></transaction>
+	</` + SuccessfulPayment + `>`
+
+	dst, _, err := parse([]byte(body))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	n, ok := dst.(*SuccessfulPaymentNotification)
+	if !ok {
+		t.Fatalf("parse returned %T, want *SuccessfulPaymentNotification", dst)
+	}
+
+	if n.Transaction.UUID != "txn-1" {
+		t.Fatalf("Transaction.UUID = %q, want %q", n.Transaction.UUID, "txn-1")
+	}
+	if n.Transaction.InvoiceNumber != 1001 {
+		t.Fatalf("Transaction.InvoiceNumber = %d, want %d", n.Transaction.InvoiceNumber, 1001)
+	}
+}